@@ -0,0 +1,354 @@
+// Copyright © 2013-14 Steve Francia <spf@spf13.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+	"github.com/blampe/goat"
+	"github.com/spf13/hugo/hugofs"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+)
+
+// Highlighter renders a block of source code as HTML. The on-disk cache in
+// front of Highlight is built against this interface, not against any
+// particular engine, so the highlighter itself can be swapped (e.g. in
+// tests) without touching the cache.
+type Highlighter interface {
+	Highlight(code, lang string, options map[string]string) (string, error)
+}
+
+// chromaHighlighter is the default Highlighter. It runs in-process, unlike
+// the Pygments integration it replaces, which forked "pygmentize" for every
+// code block.
+type chromaHighlighter struct{}
+
+// chromaPreOpenRe matches Chroma's opening <pre> tag for its chroma output,
+// e.g. `<pre class="chroma">` or, with line numbers on,
+// `<pre tabindex="0" class="chroma">`. Matching only the exact
+// `<pre class="chroma">` literal misses that second form, silently dropping
+// the language-<lang> code wrapper whenever linenos is turned on.
+var chromaPreOpenRe = regexp.MustCompile(`<pre[^>]*\bclass="chroma"[^>]*>`)
+
+func (chromaHighlighter) Highlight(code, lang string, options map[string]string) (string, error) {
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	style := styles.Get(options["style"])
+	if style == nil {
+		style = styles.Fallback
+	}
+
+	formatter := html.New(chromaFormatterOptions(options)...)
+
+	iterator, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, style, iterator); err != nil {
+		return "", err
+	}
+
+	str := buf.String()
+
+	// inject the code tag the old Pygments output carried.
+	if lang != "" {
+		if loc := chromaPreOpenRe.FindStringIndex(str); loc != nil {
+			codeTag := fmt.Sprintf(`<code class="language-%s" data-lang="%s">`, lang, lang)
+			str = str[:loc[1]] + codeTag + str[loc[1]:]
+			str = strings.Replace(str, "</pre>", "</code></pre>", 1)
+		}
+	}
+
+	return str, nil
+}
+
+// chromaFormatterOptions translates the Pygments-flavoured option map built
+// by parsePygmentsOpts (style/noclasses/hl_lines/linenos/classprefix) into
+// the equivalent Chroma html.Option values, so existing sites'
+// PygmentsOptions/PygmentsStyle/PygmentsUseClasses/hl_lines/linenos settings
+// keep working unchanged.
+func chromaFormatterOptions(options map[string]string) []html.Option {
+	opts := []html.Option{html.WithClasses(options["noclasses"] != "true")}
+
+	if prefix := options["classprefix"]; prefix != "" {
+		opts = append(opts, html.ClassPrefix(prefix))
+	}
+
+	if linenos := options["linenos"]; linenos != "" && linenos != "false" {
+		opts = append(opts, html.WithLineNumbers(true))
+	}
+
+	if hlLines := options["hl_lines"]; hlLines != "" {
+		if ranges, err := parseHlLines(hlLines); err == nil {
+			opts = append(opts, html.HighlightLines(ranges))
+		}
+	}
+
+	return opts
+}
+
+// parseHlLines parses a Pygments-style "hl_lines" value, a space-separated
+// list of line numbers (e.g. "3 5 7"), into Chroma's [low, high] range form.
+func parseHlLines(s string) ([][2]int, error) {
+	var ranges [][2]int
+	for _, f := range strings.Fields(s) {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, [2]int{n, n})
+	}
+	return ranges, nil
+}
+
+// cachingHighlighter wraps a Highlighter with the on-disk, sha1-keyed cache
+// the Pygments integration used, so repeated builds don't re-highlight
+// unchanged code blocks.
+type cachingHighlighter struct {
+	next Highlighter
+}
+
+func (c cachingHighlighter) Highlight(code, lang string, options map[string]string) (string, error) {
+	hash := sha1.New()
+	io.WriteString(hash, code)
+	io.WriteString(hash, lang)
+	io.WriteString(hash, createOptionsString(options))
+
+	fs := hugofs.OsFs
+	cacheDir := viper.GetString("CacheDir")
+	var cachefile string
+
+	if cacheDir != "" {
+		cachefile = filepath.Join(cacheDir, fmt.Sprintf("chroma-%x", hash.Sum(nil)))
+
+		exists, err := Exists(cachefile, fs)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			f, err := fs.Open(cachefile)
+			if err != nil {
+				return "", err
+			}
+			s, err := ioutil.ReadAll(f)
+			if err != nil {
+				return "", err
+			}
+			return string(s), nil
+		}
+	}
+
+	str, err := c.next.Highlight(code, lang, options)
+	if err != nil {
+		return "", err
+	}
+
+	if cachefile != "" {
+		if err := WriteToDisk(cachefile, strings.NewReader(str), fs); err != nil {
+			jww.ERROR.Print(err.Error())
+		}
+	}
+
+	return str, nil
+}
+
+// DefaultHighlighter is the Highlighter used by Highlight.
+var DefaultHighlighter Highlighter = cachingHighlighter{chromaHighlighter{}}
+
+// Highlight takes some code and returns highlighted code.
+func Highlight(code, lang, optsStr string) string {
+	options, err := parsePygmentsOpts(optsStr)
+	if err != nil {
+		jww.ERROR.Print(err.Error())
+		return code
+	}
+
+	str, err := DefaultHighlighter.Highlight(code, lang, options)
+	if err != nil {
+		jww.ERROR.Print(err.Error())
+		return code
+	}
+
+	return str
+}
+
+// CodeblockContext is passed to a CodeblockRenderHook.
+type CodeblockContext struct {
+	Code       string
+	Lang       string
+	Attributes map[string]string
+}
+
+// CodeblockRenderHook renders a fenced code block for one specific
+// language, e.g. translating a ```mermaid block into
+// <div class="mermaid">...</div>. A site registers its own via
+// CodeblockRenderHooks for whichever languages it wants handled that way;
+// ```goat gets one of these for free, see renderGoat below.
+type CodeblockRenderHook func(ctx CodeblockContext) (string, error)
+
+// CodeblockRenderHooks resolves the render hook registered for a language,
+// e.g. one backed by a layouts/_default/_markup/render-codeblock-<lang>.html
+// template.
+type CodeblockRenderHooks interface {
+	CodeblockRenderHook(lang string) (CodeblockRenderHook, bool)
+}
+
+// renderGoat is the built-in ```goat hook: it converts an ASCII-art diagram
+// to an inline SVG using the goat package, the same conversion
+// blackfriday/goldmark's own goat extension performs.
+func renderGoat(ctx CodeblockContext) (string, error) {
+	var buf bytes.Buffer
+	goat.BuildAndWriteSVG(strings.NewReader(ctx.Code), &buf, "", "")
+	return buf.String(), nil
+}
+
+// defaultCodeblockRenderHooks are consulted after the CodeblockRenderHooks
+// passed to RenderCodeblock, so a site can still override them with its own
+// render-codeblock-<lang> template.
+var defaultCodeblockRenderHooks = map[string]CodeblockRenderHook{
+	"goat": renderGoat,
+}
+
+// RenderCodeblock renders a fenced code block. If hooks is non-nil and has a
+// render hook registered for lang, that hook wins; next comes the built-in
+// hooks (currently just ```goat); otherwise the block falls back to
+// Highlight. This is the single call site markdown/template integrations
+// should use in place of calling Highlight directly, so a per-language
+// override and the highlighter never disagree about a block.
+func RenderCodeblock(hooks CodeblockRenderHooks, code, lang string, attributes map[string]string, optsStr string) (string, error) {
+	if hooks != nil {
+		if hook, ok := hooks.CodeblockRenderHook(lang); ok {
+			return hook(CodeblockContext{Code: code, Lang: lang, Attributes: attributes})
+		}
+	}
+
+	if hook, ok := defaultCodeblockRenderHooks[lang]; ok {
+		return hook(CodeblockContext{Code: code, Lang: lang, Attributes: attributes})
+	}
+
+	return Highlight(code, lang, optsStr), nil
+}
+
+var pygmentsKeywords = make(map[string]bool)
+
+func init() {
+	pygmentsKeywords["style"] = true
+	pygmentsKeywords["encoding"] = true
+	pygmentsKeywords["noclasses"] = true
+	pygmentsKeywords["hl_lines"] = true
+	pygmentsKeywords["linenos"] = true
+	pygmentsKeywords["classprefix"] = true
+	pygmentsKeywords["startinline"] = true
+}
+
+func parseOptions(options map[string]string, in string) error {
+	in = strings.Trim(in, " ")
+	if in != "" {
+		for _, v := range strings.Split(in, ",") {
+			keyVal := strings.Split(v, "=")
+			key := strings.ToLower(strings.Trim(keyVal[0], " "))
+			if len(keyVal) != 2 || !pygmentsKeywords[key] {
+				return fmt.Errorf("invalid Pygments option: %s", key)
+			}
+			options[key] = keyVal[1]
+		}
+	}
+
+	return nil
+}
+
+func createOptionsString(options map[string]string) string {
+	var keys []string
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var optionsStr string
+	for i, k := range keys {
+		optionsStr += fmt.Sprintf("%s=%s", k, options[k])
+		if i < len(options)-1 {
+			optionsStr += ","
+		}
+	}
+
+	return optionsStr
+}
+
+func parseDefaultPygmentsOpts() (map[string]string, error) {
+
+	options := make(map[string]string)
+	err := parseOptions(options, viper.GetString("PygmentsOptions"))
+	if err != nil {
+		return nil, err
+	}
+
+	if viper.IsSet("PygmentsStyle") {
+		options["style"] = viper.GetString("PygmentsStyle")
+	}
+
+	if viper.IsSet("PygmentsUseClasses") {
+		if viper.GetBool("PygmentsUseClasses") {
+			options["noclasses"] = "false"
+		} else {
+			options["noclasses"] = "true"
+		}
+
+	}
+
+	if _, ok := options["encoding"]; !ok {
+		options["encoding"] = "utf8"
+	}
+
+	return options, nil
+}
+
+// parsePygmentsOpts parses the legacy Pygments option syntax
+// ("key=value,key=value") layered on top of the site's PygmentsOptions/
+// PygmentsStyle/PygmentsUseClasses defaults, returning the merged option map
+// consumed by the configured Highlighter.
+func parsePygmentsOpts(in string) (map[string]string, error) {
+
+	options, err := parseDefaultPygmentsOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	err = parseOptions(options, in)
+	if err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
@@ -0,0 +1,103 @@
+// Copyright © 2013-14 Steve Francia <spf@spf13.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma/formatters/html"
+)
+
+func TestChromaFormatterOptions(t *testing.T) {
+	for i, this := range []struct {
+		options map[string]string
+		check   func(opts []html.Option)
+	}{
+		{map[string]string{}, func(opts []html.Option) {
+			if len(opts) != 1 {
+				t.Errorf("[%d] expected only the classes option, got %d", i, len(opts))
+			}
+		}},
+		{map[string]string{"linenos": "table"}, func(opts []html.Option) {
+			if len(opts) != 2 {
+				t.Errorf("[%d] expected linenos to add an option, got %d", i, len(opts))
+			}
+		}},
+		{map[string]string{"hl_lines": "2 4"}, func(opts []html.Option) {
+			if len(opts) != 2 {
+				t.Errorf("[%d] expected hl_lines to add an option, got %d", i, len(opts))
+			}
+		}},
+	} {
+		this.check(chromaFormatterOptions(this.options))
+	}
+}
+
+func TestParseHlLines(t *testing.T) {
+	ranges, err := parseHlLines("2 4 6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][2]int{{2, 2}, {4, 4}, {6, 6}}
+	if len(ranges) != len(expected) {
+		t.Fatalf("got %v, expected %v", ranges, expected)
+	}
+	for i := range expected {
+		if ranges[i] != expected[i] {
+			t.Errorf("[%d] got %v, expected %v", i, ranges[i], expected[i])
+		}
+	}
+
+	if _, err := parseHlLines("not-a-number"); err == nil {
+		t.Error("expected an error for a non-numeric hl_lines value")
+	}
+}
+
+func TestRenderCodeblockGoatBuiltin(t *testing.T) {
+	diagram := ".-.\n| |\n'-'\n"
+
+	out, err := RenderCodeblock(nil, diagram, "goat", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "<svg") {
+		t.Errorf("expected the built-in goat hook to render an SVG, got %q", out)
+	}
+}
+
+type stubHooks struct {
+	hook CodeblockRenderHook
+}
+
+func (s stubHooks) CodeblockRenderHook(lang string) (CodeblockRenderHook, bool) {
+	if lang != "goat" {
+		return nil, false
+	}
+	return s.hook, true
+}
+
+func TestRenderCodeblockUserHookWinsOverBuiltin(t *testing.T) {
+	hooks := stubHooks{hook: func(ctx CodeblockContext) (string, error) {
+		return "user-defined", nil
+	}}
+
+	out, err := RenderCodeblock(hooks, ".-.\n", "goat", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "user-defined" {
+		t.Errorf("expected a user-registered hook to take priority over the built-in goat hook, got %q", out)
+	}
+}
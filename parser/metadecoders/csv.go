@@ -0,0 +1,46 @@
+// Copyright 2018 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadecoders
+
+import (
+	"bytes"
+	"encoding/csv"
+)
+
+func (d Decoder) unmarshalCSV(data []byte) (interface{}, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+
+	if d.Comma != 0 {
+		r.Comma = d.Comma
+	} else {
+		r.Comma = ','
+	}
+	r.Comment = d.Comment
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]interface{}, len(records))
+	for i, record := range records {
+		row := make([]interface{}, len(record))
+		for j, v := range record {
+			row[j] = v
+		}
+		rows[i] = row
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2018 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metadecoders converts a byte slice in one of the supported
+// front matter/data formats into a Go value, and sniffs that format from
+// either a media type or the content itself.
+package metadecoders
+
+import "github.com/pkg/errors"
+
+// Format identifies one of the supported metadata/data formats.
+type Format string
+
+const (
+	ORG  Format = "org"
+	JSON Format = "json"
+	TOML Format = "toml"
+	YAML Format = "yaml"
+	CSV  Format = "csv"
+	XML  Format = "xml"
+	HCL  Format = "hcl"
+	ENV  Format = "env"
+)
+
+// Decoder decodes a byte slice in one of the Format values into a Go value.
+// It also holds the options that tweak how the CSV and XML formats are
+// decoded; the zero value matching each format's most common convention.
+type Decoder struct {
+	// Comma is the field delimiter used when decoding CSV. Defaults to ','.
+	Comma rune
+
+	// Comment, if set, marks the start of a comment line in CSV.
+	Comment rune
+
+	// XMLAttrPrefix is prepended to the map key used for an XML attribute.
+	// Defaults to "-", so <a id="1"> becomes map[string]interface{}{"-id": "1"}.
+	XMLAttrPrefix string
+
+	// XMLTextKey is the map key used for an element's character data when
+	// that element also carries attributes or child elements. Defaults to
+	// "#text".
+	XMLTextKey string
+}
+
+// Default is the Decoder used when no Options have been configured.
+var Default = Decoder{
+	Comma:         ',',
+	XMLAttrPrefix: "-",
+	XMLTextKey:    "#text",
+}
+
+// Unmarshal decodes data in the given format into a Go value, typically a
+// map[string]interface{} or []interface{}.
+func (d Decoder) Unmarshal(data []byte, f Format) (interface{}, error) {
+	switch f {
+	case JSON:
+		return d.unmarshalJSON(data)
+	case TOML:
+		return d.unmarshalTOML(data)
+	case YAML:
+		return d.unmarshalYAML(data)
+	case CSV:
+		return d.unmarshalCSV(data)
+	case XML:
+		return d.unmarshalXML(data)
+	case HCL:
+		return d.unmarshalHCL(data)
+	case ENV:
+		return d.unmarshalENV(data)
+	default:
+		return nil, errors.Errorf("unmarshal of format %q is not supported", f)
+	}
+}
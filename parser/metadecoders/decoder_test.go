@@ -0,0 +1,126 @@
+// Copyright 2018 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadecoders
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormatFromMediaType(t *testing.T) {
+	for i, this := range []struct {
+		mediaType string
+		expected  Format
+	}{
+		{"application/json", JSON},
+		{"application/json; charset=utf-8", JSON},
+		{"application/toml", TOML},
+		{"text/yaml", YAML},
+		{"text/csv", CSV},
+		{"application/xml", XML},
+		{"application/hcl", HCL},
+		{"application/x-env", ENV},
+		{"application/octet-stream", Format("")},
+	} {
+		if got := FormatFromMediaType(this.mediaType); got != this.expected {
+			t.Errorf("[%d] got %q, expected %q", i, got, this.expected)
+		}
+	}
+}
+
+func TestFormatFromContentString(t *testing.T) {
+	for i, this := range []struct {
+		data     string
+		expected Format
+	}{
+		{`{"a": "b"}`, JSON},
+		{"---\nfoo: bar\n---\n", YAML},
+		{"+++\nfoo = \"bar\"\n+++\n", TOML},
+		{"<a>b</a>", XML},
+		// Inline TOML (note the spaces around "=") must not be misread as
+		// ENV just because every non-blank line contains an "=".
+		{"title = \"Hugo\"\ndate = 2018\n", TOML},
+		// A real dotenv document: no spaces around "=".
+		{"FOO=bar\nBAZ=qux\n", ENV},
+		{"export FOO=bar\n", ENV},
+	} {
+		if got := Default.FormatFromContentString(this.data); got != this.expected {
+			t.Errorf("[%d] %q: got %q, expected %q", i, this.data, got, this.expected)
+		}
+	}
+}
+
+func TestUnmarshalXML(t *testing.T) {
+	data := `<a id="1"><b>one</b><b>two</b><c>text</c></a>`
+
+	got, err := Default.Unmarshal([]byte(data), XML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"a": map[string]interface{}{
+			"-id": "1",
+			"b":   []interface{}{"one", "two"},
+			"c":   "text",
+		},
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %#v, expected %#v", got, expected)
+	}
+}
+
+func TestUnmarshalHCL(t *testing.T) {
+	data := `name = "hugo"
+count = 2
+`
+	got, err := Default.Unmarshal([]byte(data), HCL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, ok := got.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected a map, got %T", got)
+	}
+	if m["name"] != "hugo" {
+		t.Errorf("got name=%v, expected %q", m["name"], "hugo")
+	}
+}
+
+func TestUnmarshalENV(t *testing.T) {
+	data := "export FOO=bar\nBAZ=\"quoted value\"\n# a comment\n\nQUX='single'\n"
+
+	got, err := Default.Unmarshal([]byte(data), ENV)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"FOO": "bar",
+		"BAZ": "quoted value",
+		"QUX": "single",
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %#v, expected %#v", got, expected)
+	}
+}
+
+func TestUnmarshalUnsupportedFormat(t *testing.T) {
+	if _, err := Default.Unmarshal([]byte("x"), Format("bogus")); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
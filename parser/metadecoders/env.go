@@ -0,0 +1,60 @@
+// Copyright 2018 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadecoders
+
+import (
+	"strings"
+)
+
+// unmarshalENV decodes a simple "dotenv" style document, one KEY=VALUE
+// assignment per line, into a map[string]interface{}. Blank lines, lines
+// starting with "#", and an optional leading "export " are ignored/stripped.
+// Quoted values ("..." or '...') have their surrounding quotes removed.
+func (d Decoder) unmarshalENV(data []byte) (interface{}, error) {
+	m := make(map[string]interface{})
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		key := strings.TrimSpace(parts[0])
+		val := strings.TrimSpace(parts[1])
+		val = unquoteEnvValue(val)
+
+		m[key] = val
+	}
+
+	return m, nil
+}
+
+func unquoteEnvValue(v string) string {
+	if len(v) < 2 {
+		return v
+	}
+	first, last := v[0], v[len(v)-1]
+	if (first == '"' && last == '"') || (first == '\'' && last == '\'') {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
@@ -0,0 +1,110 @@
+// Copyright 2018 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadecoders
+
+import (
+	"regexp"
+	"strings"
+)
+
+// FormatFromMediaType maps a media type, e.g. "application/json" or
+// "application/json; charset=utf-8", to a Format. It returns "" if the
+// media type isn't recognized.
+func FormatFromMediaType(mediaType string) Format {
+	mediaType = strings.ToLower(mediaType)
+	if i := strings.IndexByte(mediaType, ';'); i != -1 {
+		mediaType = mediaType[:i]
+	}
+	mediaType = strings.TrimSpace(mediaType)
+
+	switch mediaType {
+	case "application/json", "text/json":
+		return JSON
+	case "application/toml":
+		return TOML
+	case "application/yaml", "text/yaml", "application/x-yaml":
+		return YAML
+	case "text/csv":
+		return CSV
+	case "application/xml", "text/xml":
+		return XML
+	case "application/hcl", "application/x-hcl":
+		return HCL
+	case "application/x-env", "text/x-env", "application/dotenv":
+		return ENV
+	default:
+		return ""
+	}
+}
+
+// FormatFromContentString sniffs the Format from the leading bytes of data,
+// used when no media type is available (e.g. for string literals passed to
+// unmarshal).
+func (d Decoder) FormatFromContentString(data string) Format {
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return ""
+	}
+
+	switch data[0] {
+	case '{', '[':
+		return JSON
+	case '<':
+		return XML
+	}
+
+	firstLine := data
+	if i := strings.IndexAny(data, "\r\n"); i != -1 {
+		firstLine = data[:i]
+	}
+
+	if strings.HasPrefix(firstLine, "---") {
+		return YAML
+	}
+	if strings.HasPrefix(firstLine, "+++") {
+		return TOML
+	}
+
+	if looksLikeEnv(data) {
+		return ENV
+	}
+
+	return TOML
+}
+
+// envAssignmentRe matches a dotenv-style "KEY=value" assignment: an
+// identifier-like key with no space before "=", immediately followed by a
+// non-space byte. This deliberately excludes "key = value" style
+// assignments (note the spaces), which is TOML, not env — without it,
+// ordinary inline TOML like `title = "Hugo"` would be misclassified as ENV.
+var envAssignmentRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=\S`)
+
+// looksLikeEnv reports whether every non-blank, non-comment line of data
+// looks like a dotenv "KEY=value" assignment.
+func looksLikeEnv(data string) bool {
+	lines := strings.Split(data, "\n")
+	sawAssignment := false
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		if !envAssignmentRe.MatchString(line) {
+			return false
+		}
+		sawAssignment = true
+	}
+	return sawAssignment
+}
@@ -0,0 +1,107 @@
+// Copyright 2018 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadecoders
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+// unmarshalXML decodes data into a map[string]interface{} using the
+// conventional "Badgerfish"-like mapping: attributes become keys prefixed
+// with d.XMLAttrPrefix, an element's own character data is stored under
+// d.XMLTextKey when the element also has attributes or children, and
+// repeated child elements become a []interface{}.
+func (d Decoder) unmarshalXML(data []byte) (interface{}, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			v, err := d.decodeXMLElement(dec, start)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{start.Name.Local: v}, nil
+		}
+	}
+}
+
+func (d Decoder) decodeXMLElement(dec *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	attrPrefix := d.XMLAttrPrefix
+	if attrPrefix == "" {
+		attrPrefix = Default.XMLAttrPrefix
+	}
+	textKey := d.XMLTextKey
+	if textKey == "" {
+		textKey = Default.XMLTextKey
+	}
+
+	m := make(map[string]interface{})
+	for _, attr := range start.Attr {
+		m[attrPrefix+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := d.decodeXMLElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			addXMLChild(m, t.Name.Local, child)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if t.Name.Local == start.Name.Local {
+				if s := strings.TrimSpace(text.String()); s != "" {
+					if len(m) == 0 {
+						return s, nil
+					}
+					m[textKey] = s
+				}
+				if len(m) == 0 {
+					return "", nil
+				}
+				return m, nil
+			}
+		}
+	}
+}
+
+func addXMLChild(m map[string]interface{}, name string, child interface{}) {
+	existing, ok := m[name]
+	if !ok {
+		m[name] = child
+		return
+	}
+
+	if list, ok := existing.([]interface{}); ok {
+		m[name] = append(list, child)
+		return
+	}
+
+	m[name] = []interface{}{existing, child}
+}
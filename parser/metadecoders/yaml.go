@@ -0,0 +1,49 @@
+// Copyright 2018 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadecoders
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+func (d Decoder) unmarshalYAML(data []byte) (interface{}, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return cleanUpYAMLMaps(v), nil
+}
+
+// cleanUpYAMLMaps converts the map[interface{}]interface{} values produced
+// by gopkg.in/yaml.v2 into map[string]interface{}, matching what the JSON
+// and TOML decoders return, so templates can range/index uniformly.
+func cleanUpYAMLMaps(in interface{}) interface{} {
+	switch v := in.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = cleanUpYAMLMaps(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range v {
+			v[i] = cleanUpYAMLMaps(val)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pageparser
+
+import "sync"
+
+// CacheInvalidator is implemented by caches keyed off the same files a
+// page's PageDeps can reference, e.g. a frontmatter cache or
+// transform.Namespace's Unmarshal cache. DependencyTracker calls Invalidate
+// with every path it determines is now stale, so those caches don't serve
+// an entry for a file that no longer matches it.
+type CacheInvalidator interface {
+	Invalidate(path string)
+}
+
+// DependencyTracker records, for every page lexed, the PageDeps discovered
+// for it, and computes which pages need to be re-lexed and re-rendered
+// when one or more files change.
+type DependencyTracker struct {
+	mu sync.Mutex
+
+	deps map[string]PageDeps
+
+	invalidators []CacheInvalidator
+}
+
+// NewDependencyTracker creates an empty DependencyTracker. invalidators are
+// notified, via Invalidate, of every file path AffectedPages is asked
+// about.
+func NewDependencyTracker(invalidators ...CacheInvalidator) *DependencyTracker {
+	return &DependencyTracker{
+		deps:         make(map[string]PageDeps),
+		invalidators: invalidators,
+	}
+}
+
+// Record stores the PageDeps discovered while lexing/parsing page.
+func (t *DependencyTracker) Record(page string, deps PageDeps) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.deps[page] = deps
+}
+
+// Forget drops a page from the graph, e.g. when it's deleted.
+func (t *DependencyTracker) Forget(page string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.deps, page)
+}
+
+// AffectedPages returns every tracked page that is, or depends on, one of
+// the given changed files, and fires every registered CacheInvalidator for
+// each changed file.
+func (t *DependencyTracker) AffectedPages(changed ...string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	changedSet := make(map[string]bool, len(changed))
+	for _, c := range changed {
+		changedSet[c] = true
+	}
+
+	var affected []string
+	for page, deps := range t.deps {
+		if changedSet[page] || dependsOnAny(deps, changedSet) {
+			affected = append(affected, page)
+		}
+	}
+
+	for _, c := range changed {
+		for _, inv := range t.invalidators {
+			inv.Invalidate(c)
+		}
+	}
+
+	return affected
+}
+
+func dependsOnAny(deps PageDeps, changed map[string]bool) bool {
+	for _, group := range [][]string{deps.Shortcodes, deps.Partials, deps.DataFiles, deps.Resources} {
+		for _, v := range group {
+			if changed[v] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+// Copyright 2018 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pageparser
+
+import "io"
+
+// ParsedPage is the result of lexing a single page: its lexed Items, the
+// PageDeps discovered along the way, and its TableOfContents, available
+// uniformly regardless of the page's markup engine.
+type ParsedPage struct {
+	Items           []Item
+	Deps            PageDeps
+	TableOfContents *TOC
+}
+
+// Parse lexes r as a page's intro section (front matter, body, and summary
+// divider), pulling from r incrementally rather than requiring it to be
+// read into memory up front, and collects the PageDeps and TOC discovered
+// in its body text. tocOpts defaults to DefaultTOCOptions if omitted.
+func Parse(r io.Reader, tocOpts ...TOCOptions) ParsedPage {
+	opts := DefaultTOCOptions
+	if len(tocOpts) > 0 {
+		opts = tocOpts[0]
+	}
+
+	l := newPageLexer(r, 0, lexIntroSection)
+	l.run()
+
+	var pp ParsedPage
+	var body []byte
+
+	for {
+		item := l.nextItem()
+		pp.Items = append(pp.Items, item)
+		if item.typ == tText {
+			body = append(body, item.Val...)
+		}
+		if item.typ == tEOF || item.typ == tError {
+			break
+		}
+	}
+
+	pp.Deps = extractPageDeps(body)
+	pp.TableOfContents = TableOfContents(pp.Items, opts)
+
+	return pp
+}
+
+// ParseAndTrack lexes r as the page at path and records the PageDeps it
+// discovers in tracker, so a later change to one of those dependencies can
+// be resolved back to path via tracker.AffectedPages. templates is the raw
+// content of every template in the page's resolved layout chain (its
+// base/single/list template and whatever partials those in turn call):
+// that's where partial/getJSON/getCSV/transform.Unmarshal calls actually
+// live, so they can't be found by scanning the page's own body alone.
+func ParseAndTrack(path string, r io.Reader, tracker *DependencyTracker, templates ...[]byte) []Item {
+	pp := Parse(r)
+	if tracker != nil {
+		tracker.Record(path, pp.Deps.Merge(TemplateDeps(templates...)))
+	}
+	return pp.Items
+}
@@ -0,0 +1,155 @@
+// Copyright 2018 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pageparser
+
+import (
+	"path"
+	"regexp"
+)
+
+// PageDeps records the build-time dependencies discovered while lexing and
+// parsing a single page: the shortcodes, partials, data files, and other
+// resources it referenced. Every entry is normalized to a path comparable
+// against a changed file's path (e.g. a "figure" shortcode invocation
+// becomes "layouts/shortcodes/figure.html"), so DependencyTracker can use
+// plain string equality to decide which pages need to be re-lexed and
+// re-rendered when a single file changes.
+type PageDeps struct {
+	Shortcodes []string
+	Partials   []string
+	DataFiles  []string
+	Resources  []string
+}
+
+// These patterns match the call syntax for the constructs a page can
+// depend on. They're a lightweight sweep over source text, not a full
+// template parse: it's fine to over-match (and invalidate a page that
+// didn't really need it), it's not fine to under-match.
+//
+// shortcodeRe is matched against a page's body, where shortcodes are
+// invoked (e.g. "{{< figure src=... >}}"). partialRe and dataFileRe are
+// matched against the page's resolved layout templates instead: a
+// partial/getJSON/getCSV/transform.Unmarshal call lives in the template
+// that renders the page, never in the page's own content.
+var (
+	shortcodeRe = regexp.MustCompile(`\{\{[%<]-?\s*([a-zA-Z0-9_-]+)`)
+	partialRe   = regexp.MustCompile(`partial(?:Cached)?\s+"([^"]+)"`)
+	dataFileRe  = regexp.MustCompile(`(getJSON|getCSV|transform\.Unmarshal)\s*[\s(]\s*"([^"]+)"`)
+	resourceRe  = regexp.MustCompile(`\.Resources\.(?:GetMatch|Get)\s+"([^"]+)"`)
+)
+
+// extractPageDeps scans a page's lexed body text — where shortcode
+// invocations and page-bundle resource lookups appear — for the
+// dependencies recorded in PageDeps. It does not look for partials or data
+// files: those live in templates, see TemplateDeps.
+func extractPageDeps(body []byte) PageDeps {
+	return PageDeps{
+		Shortcodes: normalizeAll(uniqueMatches(shortcodeRe, body), shortcodePath),
+		Resources:  uniqueMatches(resourceRe, body),
+	}
+}
+
+// TemplateDeps scans the layout templates used to render a page (its full
+// lookup-order chain: base/single/list templates and any partials they in
+// turn call) for the partial and data-file dependencies that actually live
+// there, normalizing each to a path comparable against a changed file.
+func TemplateDeps(templates ...[]byte) PageDeps {
+	var deps PageDeps
+	for _, tmpl := range templates {
+		deps.Partials = append(deps.Partials, uniqueMatches(partialRe, tmpl)...)
+		for _, m := range dataFileRe.FindAllSubmatch(tmpl, -1) {
+			deps.DataFiles = append(deps.DataFiles, dataFilePath(string(m[1]), string(m[2])))
+		}
+	}
+	deps.Partials = dedupe(normalizeAll(deps.Partials, partialPath))
+	deps.DataFiles = dedupe(deps.DataFiles)
+	return deps
+}
+
+// Merge folds other's dependencies into d, deduplicating as it goes. It's
+// used to combine a page's own PageDeps (shortcodes, resources) with the
+// TemplateDeps discovered from its layout chain before recording the
+// result with a DependencyTracker.
+func (d PageDeps) Merge(other PageDeps) PageDeps {
+	return PageDeps{
+		Shortcodes: dedupe(append(append([]string{}, d.Shortcodes...), other.Shortcodes...)),
+		Partials:   dedupe(append(append([]string{}, d.Partials...), other.Partials...)),
+		DataFiles:  dedupe(append(append([]string{}, d.DataFiles...), other.DataFiles...)),
+		Resources:  dedupe(append(append([]string{}, d.Resources...), other.Resources...)),
+	}
+}
+
+// shortcodePath normalizes a shortcode invocation's name to the template
+// file that implements it.
+func shortcodePath(name string) string {
+	return path.Join("layouts/shortcodes", name+".html")
+}
+
+// partialPath normalizes a partial call's name to the template file it
+// resolves to, defaulting to ".html" when the call didn't name an
+// extension explicitly (the common case: {{ partial "foo" . }}).
+func partialPath(name string) string {
+	if path.Ext(name) == "" {
+		name += ".html"
+	}
+	return path.Join("layouts/partials", name)
+}
+
+// dataFilePath normalizes a getJSON/getCSV/transform.Unmarshal call's
+// argument to the data file it reads. getJSON/getCSV always read
+// extensionless names under data/; transform.Unmarshal takes a full
+// resource path already, so it's left as-is.
+func dataFilePath(verb, name string) string {
+	switch verb {
+	case "getJSON":
+		return path.Join("data", name+".json")
+	case "getCSV":
+		return path.Join("data", name+".csv")
+	default: // transform.Unmarshal
+		return name
+	}
+}
+
+func normalizeAll(names []string, normalize func(string) string) []string {
+	out := make([]string, len(names))
+	for i, n := range names {
+		out[i] = normalize(n)
+	}
+	return out
+}
+
+func dedupe(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	var out []string
+	for _, v := range in {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func uniqueMatches(re *regexp.Regexp, body []byte) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, m := range re.FindAllSubmatch(body, -1) {
+		v := string(m[len(m)-1])
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pageparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractPageDepsShortcodesAndResources(t *testing.T) {
+	body := []byte(`Some text {{< figure src="a.png" >}} and {{% notice %}} and again {{< figure src="b.png" >}}.
+
+See .Resources.Get "images/a.png" and .Resources.GetMatch "images/*.png".`)
+
+	deps := extractPageDeps(body)
+
+	wantShortcodes := []string{"layouts/shortcodes/figure.html", "layouts/shortcodes/notice.html"}
+	if !reflect.DeepEqual(deps.Shortcodes, wantShortcodes) {
+		t.Errorf("Shortcodes: got %v, expected %v", deps.Shortcodes, wantShortcodes)
+	}
+
+	wantResources := []string{"images/a.png", "images/*.png"}
+	if !reflect.DeepEqual(deps.Resources, wantResources) {
+		t.Errorf("Resources: got %v, expected %v", deps.Resources, wantResources)
+	}
+
+	if deps.Partials != nil || deps.DataFiles != nil {
+		t.Errorf("expected no partials/data files from a page body scan, got %+v", deps)
+	}
+}
+
+func TestTemplateDepsPartialsAndDataFiles(t *testing.T) {
+	tmpl := []byte(`{{ partial "header" . }}{{ partialCached "footer.html" . }}
+{{ $data := getJSON "authors" }}{{ $rows := getCSV "stats" }}
+{{ transform.Unmarshal "data/config.toml" }}`)
+
+	deps := TemplateDeps(tmpl)
+
+	wantPartials := []string{"layouts/partials/header.html", "layouts/partials/footer.html"}
+	if !reflect.DeepEqual(deps.Partials, wantPartials) {
+		t.Errorf("Partials: got %v, expected %v", deps.Partials, wantPartials)
+	}
+
+	wantDataFiles := []string{"data/authors.json", "data/stats.csv", "data/config.toml"}
+	if !reflect.DeepEqual(deps.DataFiles, wantDataFiles) {
+		t.Errorf("DataFiles: got %v, expected %v", deps.DataFiles, wantDataFiles)
+	}
+}
+
+func TestDependencyTrackerAffectedPages(t *testing.T) {
+	tracker := NewDependencyTracker()
+
+	bodyDeps := extractPageDeps([]byte(`{{< figure src="a.png" >}}`))
+	tmplDeps := TemplateDeps([]byte(`{{ partial "header" . }}`))
+	tracker.Record("content/post.md", bodyDeps.Merge(tmplDeps))
+
+	affected := tracker.AffectedPages("layouts/partials/header.html")
+	if len(affected) != 1 || affected[0] != "content/post.md" {
+		t.Fatalf("expected content/post.md to be affected by its partial changing, got %v", affected)
+	}
+
+	affected = tracker.AffectedPages("layouts/partials/unrelated.html")
+	if len(affected) != 0 {
+		t.Fatalf("expected no pages affected by an unrelated partial, got %v", affected)
+	}
+
+	affected = tracker.AffectedPages("layouts/shortcodes/figure.html")
+	if len(affected) != 1 || affected[0] != "content/post.md" {
+		t.Fatalf("expected content/post.md to be affected by its shortcode changing, got %v", affected)
+	}
+}
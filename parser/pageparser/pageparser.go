@@ -0,0 +1,452 @@
+// Copyright 2018 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pageparser
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// itemType identifies the type of a lexed Item.
+type itemType int
+
+const (
+	tError itemType = iota
+	tEOF
+
+	tHTMLLead
+
+	tFrontMatterTOML
+	tFrontMatterYAML
+	tFrontMatterJSON
+	tFrontMatterORG
+
+	tSummaryDivider
+	tSummaryDividerOrg
+
+	tText
+)
+
+const (
+	summaryDivider    = "<!--more-->"
+	summaryDividerOrg = "# more"
+)
+
+// Item is a single lexed token: its type, its byte offset in the source,
+// and its raw bytes.
+type Item struct {
+	typ itemType
+	pos int
+	Val []byte
+}
+
+// stateFunc is one step of the lexer state machine. It returns the next
+// step, or nil once lexing is done.
+type stateFunc func(*pageLexer) stateFunc
+
+const eof = 0
+
+// pageLexer lexes the intro section (front matter, body, and summary
+// divider) of a page. It pulls input from an io.Reader in chunks rather
+// than requiring the whole page up front, and run drives the state machine
+// in its own goroutine, emitting each Item on a channel as soon as it's
+// produced: nextItem blocks until the next Item is ready, so a caller pulls
+// lazily instead of waiting for the whole page to be tokenized. buf only
+// ever holds the bytes of the token currently being lexed — everything
+// before it is released once emitted — so memory use stays bounded even
+// for very large content files.
+type pageLexer struct {
+	r    *bufio.Reader
+	buf  []byte
+	base int // absolute input offset of buf[0]
+	eof  bool
+
+	pos   int // absolute position of the read cursor
+	start int // absolute position of the start of the current token
+
+	org bool // an ORG front matter/summary divider was detected
+
+	state stateFunc
+	items chan Item
+}
+
+func newPageLexer(r io.Reader, pos int, start stateFunc) *pageLexer {
+	return &pageLexer{
+		r:     bufio.NewReader(r),
+		base:  pos,
+		pos:   pos,
+		start: pos,
+		state: start,
+		items: make(chan Item),
+	}
+}
+
+// fill reads another chunk from the underlying reader into buf. It reports
+// whether it read anything.
+func (l *pageLexer) fill() bool {
+	if l.eof {
+		return false
+	}
+	chunk := make([]byte, 4096)
+	n, err := l.r.Read(chunk)
+	if n > 0 {
+		l.buf = append(l.buf, chunk[:n]...)
+	}
+	if err != nil {
+		l.eof = true
+	}
+	return n > 0
+}
+
+// ensure grows buf, pulling from the reader, until it holds at least n
+// bytes (in absolute terms) or the input is exhausted.
+func (l *pageLexer) ensure(n int) bool {
+	for l.base+len(l.buf) < n {
+		if !l.fill() {
+			return l.base+len(l.buf) >= n
+		}
+	}
+	return true
+}
+
+// at converts an absolute position into an index into buf.
+func (l *pageLexer) at(pos int) int {
+	return pos - l.base
+}
+
+func (l *pageLexer) peek() byte {
+	if !l.ensure(l.pos + 1) {
+		return eof
+	}
+	return l.buf[l.at(l.pos)]
+}
+
+func (l *pageLexer) next() byte {
+	b := l.peek()
+	if b != eof {
+		l.pos++
+	}
+	return b
+}
+
+func (l *pageLexer) hasPrefix(s string) bool {
+	if !l.ensure(l.pos + len(s)) {
+		return false
+	}
+	i := l.at(l.pos)
+	if i+len(s) > len(l.buf) {
+		return false
+	}
+	return bytes.HasPrefix(l.buf[i:], []byte(s))
+}
+
+// slice returns the bytes between the two absolute positions, both of
+// which must still be within buf (i.e. not before l.start).
+func (l *pageLexer) slice(from, to int) []byte {
+	return l.buf[l.at(from):l.at(to)]
+}
+
+// readLine consumes up to and including the next '\n' (or to EOF if none is
+// found), returning the consumed bytes with the '\n' itself excluded.
+func (l *pageLexer) readLine() []byte {
+	start := l.pos
+	for {
+		b := l.peek()
+		if b == eof {
+			return l.slice(start, l.pos)
+		}
+		if b == '\n' {
+			line := l.slice(start, l.pos)
+			l.next()
+			return line
+		}
+		l.next()
+	}
+}
+
+// emit sends the token since the last emit on items and releases the bytes
+// behind it: no state ever looks back past the start of the token it's
+// currently lexing, so buf need not grow past a single token's worth of
+// input plus whatever lookahead is in flight.
+func (l *pageLexer) emit(t itemType) {
+	val := make([]byte, l.pos-l.start)
+	copy(val, l.slice(l.start, l.pos))
+	l.items <- Item{t, l.start, val}
+	l.start = l.pos
+	l.release()
+}
+
+// release drops buf's bytes before l.start, the earliest position any
+// state still needs.
+func (l *pageLexer) release() {
+	drop := l.at(l.start)
+	if drop <= 0 {
+		return
+	}
+	l.buf = l.buf[drop:]
+	l.base = l.start
+}
+
+// run drives the state machine in its own goroutine and closes items once
+// lexing is done, so nextItem can start handing out tokens before the rest
+// of the page has even been read off the underlying io.Reader.
+func (l *pageLexer) run() {
+	go func() {
+		for state := l.state; state != nil; {
+			state = state(l)
+		}
+		close(l.items)
+	}()
+}
+
+func (l *pageLexer) nextItem() Item {
+	item, ok := <-l.items
+	if !ok {
+		return Item{tEOF, l.pos, make([]byte, 0)}
+	}
+	return item
+}
+
+// lexIntroSection is the lexer's start state: it identifies raw HTML pages
+// and each supported front matter format, then hands off to lexBody.
+func lexIntroSection(l *pageLexer) stateFunc {
+	if l.peek() == eof {
+		l.emit(tEOF)
+		return nil
+	}
+
+	save := l.pos
+	for l.peek() == ' ' {
+		l.next()
+	}
+	if l.peek() == '<' {
+		l.next()
+		l.emit(tHTMLLead)
+		return lexHTMLDocument
+	}
+	l.pos = save
+
+	switch {
+	case l.hasPrefix("---"):
+		return lexFrontMatterFenced(tFrontMatterYAML, "---")
+	case l.hasPrefix("+++"):
+		return lexFrontMatterFenced(tFrontMatterTOML, "+++")
+	case l.hasPrefix("{"):
+		return lexFrontMatterJSON
+	case l.hasPrefix("#+"), l.hasPrefix("\n#+"), l.hasPrefix("\r\n#+"):
+		l.org = true
+		return lexFrontMatterOrg
+	default:
+		return lexBody
+	}
+}
+
+// lexHTMLDocument is used for pages that are raw HTML: everything after the
+// leading "<" is emitted as a single text item, with no front matter or
+// summary-divider handling.
+func lexHTMLDocument(l *pageLexer) stateFunc {
+	for l.peek() != eof {
+		l.next()
+	}
+	l.emit(tText)
+	l.emit(tEOF)
+	return nil
+}
+
+// lexFrontMatterFenced returns a stateFunc that consumes a "---"/"+++"
+// fenced front matter block of the given type.
+func lexFrontMatterFenced(typ itemType, fence string) stateFunc {
+	return func(l *pageLexer) stateFunc {
+		l.readLine() // the opening fence line
+		l.start = l.pos
+
+		for {
+			if l.peek() == eof {
+				l.emit(typ)
+				l.emit(tEOF)
+				return nil
+			}
+
+			lineStart := l.pos
+			line := bytes.TrimRight(l.readLine(), "\r")
+
+			if string(line) == fence {
+				savedPos := l.pos
+				l.pos = lineStart
+				l.emit(typ)
+				l.pos = savedPos
+				l.start = l.pos
+				return lexBody
+			}
+		}
+	}
+}
+
+// lexFrontMatterJSON consumes a JSON front matter object, honouring quoted
+// strings (including escaped quotes) so that braces inside string values
+// don't throw off the balance count.
+func lexFrontMatterJSON(l *pageLexer) stateFunc {
+	depth := 0
+	inString := false
+	escaped := false
+
+	for {
+		b := l.next()
+		if b == eof {
+			break
+		}
+
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch b {
+		case '"':
+			inString = true
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				goto closed
+			}
+		}
+	}
+closed:
+	// The rest of this line (e.g. a trailing "\r\n") belongs to the front
+	// matter token too, matching the fenced formats.
+	for {
+		b := l.peek()
+		if b == eof {
+			break
+		}
+		l.next()
+		if b == '\n' {
+			break
+		}
+	}
+
+	l.emit(tFrontMatterJSON)
+	return lexBody
+}
+
+// lexFrontMatterOrg consumes ORG front matter: any leading blank lines,
+// followed by a run of "#+KEY: value" directive lines.
+func lexFrontMatterOrg(l *pageLexer) stateFunc {
+	seenDirective := false
+
+	for {
+		save := l.pos
+		line := bytes.TrimRight(l.readLine(), "\r")
+
+		switch {
+		case bytes.HasPrefix(line, []byte("#+")):
+			seenDirective = true
+		case len(line) == 0 && !seenDirective:
+			// leading blank line before the first directive.
+		default:
+			l.pos = save
+			l.emit(tFrontMatterORG)
+			return lexBody
+		}
+
+		if l.peek() == eof {
+			l.emit(tFrontMatterORG)
+			l.emit(tEOF)
+			return nil
+		}
+	}
+}
+
+// lexBody scans the page body for the summary divider, emitting the text
+// before and after it.
+func lexBody(l *pageLexer) stateFunc {
+	if l.org {
+		return lexBodyOrg(l)
+	}
+	return lexBodyDefault(l)
+}
+
+func lexBodyDefault(l *pageLexer) stateFunc {
+	for {
+		if l.hasPrefix(summaryDivider) {
+			if l.pos > l.start {
+				l.emit(tText)
+			}
+			for range summaryDivider {
+				l.next()
+			}
+			l.emit(tSummaryDivider)
+			continue
+		}
+
+		if l.next() == eof {
+			if l.pos > l.start {
+				l.emit(tText)
+			}
+			l.emit(tEOF)
+			return nil
+		}
+	}
+}
+
+// lexBodyOrg is lexBodyDefault's ORG-mode equivalent: the summary divider
+// there is a line containing exactly "# more", not an HTML comment.
+func lexBodyOrg(l *pageLexer) stateFunc {
+	lineStart := l.pos
+	for {
+		b := l.next()
+		if b == eof {
+			if l.pos > l.start {
+				l.emit(tText)
+			}
+			l.emit(tEOF)
+			return nil
+		}
+		if b != '\n' {
+			continue
+		}
+
+		lineEnd := l.pos - 1
+		line := l.slice(lineStart, lineEnd)
+		if len(line) > 0 && line[len(line)-1] == '\r' {
+			line = line[:len(line)-1]
+		}
+
+		if string(line) == summaryDividerOrg {
+			savedPos := l.pos
+
+			l.pos = lineStart
+			if l.pos > l.start {
+				l.emit(tText)
+			}
+
+			l.pos = lineStart + len(line)
+			l.emit(tSummaryDividerOrg)
+
+			l.pos = savedPos
+		}
+
+		lineStart = l.pos
+	}
+}
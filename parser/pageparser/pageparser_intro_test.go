@@ -14,6 +14,7 @@
 package pageparser
 
 import (
+	"bytes"
 	"fmt"
 	"reflect"
 	"strings"
@@ -40,6 +41,7 @@ var (
 	tstSomeText            = nti(tText, "\nSome text.\n")
 	tstSummaryDivider      = nti(tSummaryDivider, "<!--more-->")
 	tstSummaryDividerOrg   = nti(tSummaryDividerOrg, "# more")
+	tstEOF                 = nti(tEOF, "")
 
 	tstORG = `
 #+TITLE: T1
@@ -78,7 +80,7 @@ func TestFrontMatter(t *testing.T) {
 }
 
 func collect(input []byte, skipFrontMatter bool, stateStart stateFunc) (items []Item) {
-	l := newPageLexer(input, 0, stateStart)
+	l := newPageLexer(bytes.NewReader(input), 0, stateStart)
 	l.run()
 
 	for {
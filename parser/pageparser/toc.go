@@ -0,0 +1,309 @@
+// Copyright 2018 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pageparser
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TOCEntry is one heading in a page's table of contents.
+type TOCEntry struct {
+	Level    int
+	ID       string
+	Title    string
+	Offset   int
+	Children []*TOCEntry
+}
+
+// TOC is a page's table of contents: a forest of TOCEntry trees, with the
+// top-level headings as the slice itself and everything else nested under
+// Children.
+type TOC struct {
+	Entries []*TOCEntry
+}
+
+// TOCOptions bounds which heading levels (1-6) TableOfContents includes.
+// The zero value of either field falls back to the full range.
+type TOCOptions struct {
+	MinDepth int
+	MaxDepth int
+}
+
+// DefaultTOCOptions includes every heading level.
+var DefaultTOCOptions = TOCOptions{MinDepth: 1, MaxDepth: 6}
+
+type heading struct {
+	level  int
+	title  string
+	offset int
+}
+
+// These match a heading regardless of the page's markup engine: Markdown
+// ATX headings ("## Title"), raw HTML headings ("<h2>Title</h2>"), and ORG
+// headings ("** Title"). Running all three over the same body text is what
+// lets ORG and future formats share TOC support without depending on any
+// one renderer's internals.
+var (
+	atxHeadingRe  = regexp.MustCompile(`(?m)^[ \t]{0,3}(#{1,6})[ \t]+(.+?)[ \t]*#*[ \t]*$`)
+	htmlHeadingRe = regexp.MustCompile(`(?is)<h([1-6])(?:\s[^>]*)?>(.*?)</h[1-6]>`)
+	orgHeadingRe  = regexp.MustCompile(`(?m)^(\*{1,6})[ \t]+(.+)$`)
+	htmlTagRe     = regexp.MustCompile(`<[^>]*>`)
+	slugInvalidRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+	// fenceLineRe matches a fenced code block's opening/closing delimiter
+	// line ("```", "~~~~go", etc).
+	fenceLineRe = regexp.MustCompile("^[ \t]{0,3}(`{3,}|~{3,})")
+	// indentedCodeLineRe matches one line of an indented code block.
+	indentedCodeLineRe = regexp.MustCompile(`^(?:[ ]{4}|\t)`)
+	// codeSpanRe matches an inline code span such as "` + "`" + `foo` + "`" + `".
+	codeSpanRe = regexp.MustCompile("`+[^`\n]*`+")
+)
+
+// TableOfContents is a post-lex walker: it scans the tText items produced
+// by Parse for heading tokens and builds the nested TOC tree, with stable
+// slugified anchor ids (duplicates disambiguated as "-1", "-2", ...).
+//
+// Markdown and raw-HTML headings are always matched; ORG's "* Title" form
+// is only matched when items shows the page itself is ORG (a
+// tFrontMatterORG or tSummaryDividerOrg item is present) — otherwise an
+// ordinary Markdown bullet list ("* item") would be misread as a level-1
+// heading on every non-ORG page.
+func TableOfContents(items []Item, opts TOCOptions) *TOC {
+	if opts.MinDepth == 0 {
+		opts.MinDepth = DefaultTOCOptions.MinDepth
+	}
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = DefaultTOCOptions.MaxDepth
+	}
+
+	isOrg := false
+	for _, item := range items {
+		if item.typ == tFrontMatterORG || item.typ == tSummaryDividerOrg {
+			isOrg = true
+			break
+		}
+	}
+
+	var headings []heading
+	for _, item := range items {
+		if item.typ != tText {
+			continue
+		}
+		headings = append(headings, headingsIn(item, isOrg)...)
+	}
+
+	return buildTOCTree(headings, opts)
+}
+
+func headingsIn(item Item, isOrg bool) []heading {
+	type match struct {
+		start int
+		h     heading
+	}
+
+	excluded := codeExcludedRanges(item.Val)
+
+	var matches []match
+
+	for _, m := range atxHeadingRe.FindAllSubmatchIndex(item.Val, -1) {
+		if inRanges(m[0], excluded) {
+			continue
+		}
+		level := m[3] - m[2]
+		title := strings.TrimSpace(string(item.Val[m[4]:m[5]]))
+		matches = append(matches, match{m[0], heading{level, title, item.pos + m[0]}})
+	}
+
+	for _, m := range htmlHeadingRe.FindAllSubmatchIndex(item.Val, -1) {
+		if inRanges(m[0], excluded) {
+			continue
+		}
+		level := int(item.Val[m[2]] - '0')
+		title := strings.TrimSpace(htmlTagRe.ReplaceAllString(string(item.Val[m[4]:m[5]]), ""))
+		matches = append(matches, match{m[0], heading{level, title, item.pos + m[0]}})
+	}
+
+	if isOrg {
+		for _, m := range orgHeadingRe.FindAllSubmatchIndex(item.Val, -1) {
+			if inRanges(m[0], excluded) {
+				continue
+			}
+			level := m[3] - m[2]
+			title := strings.TrimSpace(string(item.Val[m[4]:m[5]]))
+			matches = append(matches, match{m[0], heading{level, title, item.pos + m[0]}})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+
+	out := make([]heading, len(matches))
+	for i, m := range matches {
+		out[i] = m.h
+	}
+	return out
+}
+
+// byteRange is a half-open [start, end) span of a tText item's Val that
+// headingsIn should ignore: a fenced or indented code block, or an inline
+// code span.
+type byteRange struct {
+	start, end int
+}
+
+// codeExcludedRanges finds every fenced code block, indented code block,
+// and inline code span in val, so headingsIn can skip a shell "# comment"
+// or a literal "<h2>" that only looks like a heading because it's inside
+// one.
+func codeExcludedRanges(val []byte) []byteRange {
+	var ranges []byteRange
+
+	lineStarts := []int{0}
+	for i, b := range val {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	lineEnd := func(i int) int {
+		if i == len(lineStarts)-1 {
+			return len(val)
+		}
+		return lineStarts[i+1] - 1 // exclude the '\n'
+	}
+
+	for i := 0; i < len(lineStarts); i++ {
+		line := val[lineStarts[i]:lineEnd(i)]
+
+		if m := fenceLineRe.FindSubmatch(line); m != nil {
+			fenceChar, fenceLen := m[1][0], len(m[1])
+			start := lineStarts[i]
+			end := len(val)
+			j := i + 1
+			for ; j < len(lineStarts); j++ {
+				closeLine := val[lineStarts[j]:lineEnd(j)]
+				if isFenceClose(closeLine, fenceChar, fenceLen) {
+					end = lineEnd(j)
+					break
+				}
+			}
+			ranges = append(ranges, byteRange{start, end})
+			i = j
+			continue
+		}
+
+		if indentedCodeLineRe.Match(line) {
+			start := lineStarts[i]
+			j := i
+			for j < len(lineStarts) && indentedCodeLineRe.Match(val[lineStarts[j]:lineEnd(j)]) {
+				j++
+			}
+			ranges = append(ranges, byteRange{start, lineEnd(j - 1)})
+			i = j - 1
+			continue
+		}
+	}
+
+	for _, m := range codeSpanRe.FindAllIndex(val, -1) {
+		ranges = append(ranges, byteRange{m[0], m[1]})
+	}
+
+	return ranges
+}
+
+// isFenceClose reports whether line closes a fence opened with fenceChar
+// repeated fenceLen times: the same character, at least as many repeats,
+// and nothing but that fence on the line.
+func isFenceClose(line []byte, fenceChar byte, fenceLen int) bool {
+	trimmed := bytes.TrimLeft(line, " \t")
+	if len(trimmed) < 3 {
+		return false
+	}
+	trimmed = bytes.TrimRight(trimmed, " \t")
+	n := 0
+	for n < len(trimmed) && trimmed[n] == fenceChar {
+		n++
+	}
+	return n == len(trimmed) && n >= fenceLen && n >= 3
+}
+
+func inRanges(pos int, ranges []byteRange) bool {
+	for _, r := range ranges {
+		if pos >= r.start && pos < r.end {
+			return true
+		}
+	}
+	return false
+}
+
+func buildTOCTree(headings []heading, opts TOCOptions) *TOC {
+	toc := &TOC{}
+	seen := make(map[string]bool)
+
+	var stack []*TOCEntry
+
+	for _, h := range headings {
+		if h.level < opts.MinDepth || h.level > opts.MaxDepth {
+			continue
+		}
+
+		entry := &TOCEntry{
+			Level:  h.level,
+			Title:  h.title,
+			Offset: h.offset,
+			ID:     uniqueSlug(slugify(h.title), seen),
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].Level >= entry.Level {
+			stack = stack[:len(stack)-1]
+		}
+
+		if len(stack) == 0 {
+			toc.Entries = append(toc.Entries, entry)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, entry)
+		}
+
+		stack = append(stack, entry)
+	}
+
+	return toc
+}
+
+func slugify(title string) string {
+	s := strings.ToLower(title)
+	s = slugInvalidRe.ReplaceAllString(s, "-")
+	s = strings.Trim(s, "-")
+	if s == "" {
+		s = "heading"
+	}
+	return s
+}
+
+func uniqueSlug(base string, seen map[string]bool) string {
+	if !seen[base] {
+		seen[base] = true
+		return base
+	}
+	for n := 1; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", base, n)
+		if !seen[candidate] {
+			seen[candidate] = true
+			return candidate
+		}
+	}
+}
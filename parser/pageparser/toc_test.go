@@ -0,0 +1,122 @@
+// Copyright 2018 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pageparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func titles(toc *TOC) []string {
+	var out []string
+	var walk func([]*TOCEntry)
+	walk = func(entries []*TOCEntry) {
+		for _, e := range entries {
+			out = append(out, e.Title)
+			walk(e.Children)
+		}
+	}
+	walk(toc.Entries)
+	return out
+}
+
+func ids(toc *TOC) []string {
+	var out []string
+	var walk func([]*TOCEntry)
+	walk = func(entries []*TOCEntry) {
+		for _, e := range entries {
+			out = append(out, e.ID)
+			walk(e.Children)
+		}
+	}
+	walk(toc.Entries)
+	return out
+}
+
+func parseTOC(t *testing.T, body string) *TOC {
+	t.Helper()
+	pp := Parse(strings.NewReader(body))
+	return pp.TableOfContents
+}
+
+func TestTOCSkipsFencedAndIndentedCode(t *testing.T) {
+	body := "# Real Heading\n\n" +
+		"```bash\n# not a heading\n```\n\n" +
+		"    # also not a heading (indented code)\n\n" +
+		"## Another Real Heading\n"
+
+	got := titles(parseTOC(t, body))
+	want := []string{"Real Heading", "Another Real Heading"}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %v, expected %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("[%d] got %q, expected %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTOCSkipsCodeSpanHTML(t *testing.T) {
+	body := "# Heading\n\nSee `<h2>not a heading</h2>` for details.\n"
+
+	got := titles(parseTOC(t, body))
+	want := []string{"Heading"}
+
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Fatalf("got %v, expected %v", got, want)
+	}
+}
+
+func TestTOCDisambiguatesDuplicateSlugs(t *testing.T) {
+	body := "# Foo\n\n# Foo\n\n# Foo-1\n"
+
+	got := ids(parseTOC(t, body))
+	seen := make(map[string]bool)
+	for _, id := range got {
+		if seen[id] {
+			t.Fatalf("duplicate id %q in %v", id, got)
+		}
+		seen[id] = true
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %v, expected 3 unique ids", got)
+	}
+}
+
+func TestTOCIgnoresOrgHeadingsOnNonOrgPages(t *testing.T) {
+	body := "Intro text.\n\n* just a bullet list item\n\nMore text.\n"
+
+	toc := parseTOC(t, body)
+	if len(toc.Entries) != 0 {
+		t.Fatalf("expected no headings on a non-ORG page, got %v", titles(toc))
+	}
+}
+
+func TestTOCMatchesOrgHeadingsOnOrgPages(t *testing.T) {
+	body := "#+TITLE: T1\n\n* Top\n** Sub\n"
+
+	got := titles(parseTOC(t, body))
+	want := []string{"Top", "Sub"}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %v, expected %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("[%d] got %q, expected %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package target
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+type Publisher interface {
+	Publish(string, io.Reader) error
+}
+
+type Translator interface {
+	Translate(string) (string, error)
+}
+
+type Output interface {
+	Publisher
+	Translator
+}
+
+// Filesystem is the terminal Output in a publish pipeline: it translates
+// ugly/pretty URLs, runs the configured Stages over the content, and writes
+// the result to PublishDir. Writes are atomic, so an interrupted build never
+// leaves a partially written page behind; see writeAtomic.
+type Filesystem struct {
+	UglyUrls         bool
+	DefaultExtension string
+	PublishDir       string
+
+	// Stages run, in order, on every Publish before the result reaches
+	// disk. A stage may transform the content it passes on (e.g. minify)
+	// and/or act as a side-effecting tap that writes its own sibling file
+	// (e.g. GzipStage, BrotliStage) or records an entry elsewhere (e.g.
+	// ManifestStage), without altering what later stages see.
+	Stages []Stage
+}
+
+func (fs *Filesystem) Publish(p string, r io.Reader) (err error) {
+
+	translated, err := fs.Translate(p)
+	if err != nil {
+		return
+	}
+
+	dir, _ := path.Split(translated)
+	dest := filepath.Join(fs.PublishDir, filepath.FromSlash(dir))
+	ospath := filepath.FromSlash(dest)
+
+	// Stages may write their own sibling files alongside the translated
+	// path (GzipStage, BrotliStage, ManifestStage's fingerprinted copy), so
+	// the directory needs to exist before they run, not just before the
+	// final write below.
+	err = os.MkdirAll(ospath, 0764) // rwx, rw, r
+	if err != nil {
+		return
+	}
+
+	for _, stage := range fs.Stages {
+		r, err = stage.Publish(translated, r)
+		if err != nil {
+			return
+		}
+	}
+
+	return writeAtomic(filepath.Join(fs.PublishDir, filepath.FromSlash(translated)), r)
+}
+
+// writeAtomic copies r into a temp file alongside dest and renames it into
+// place, so a reader can never observe a half-written dest.
+func writeAtomic(dest string, r io.Reader) error {
+	dir := filepath.Dir(dest)
+
+	tmp, err := ioutil.TempFile(dir, ".tmp-"+filepath.Base(dest))
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	return os.Rename(tmpName, dest)
+}
+
+func (fs *Filesystem) Translate(src string) (dest string, err error) {
+	if src == "/" {
+		return "index.html", nil
+	}
+
+	dir, file := path.Split(src)
+	ext := fs.extension(path.Ext(file))
+	name := filename(file)
+
+	if fs.UglyUrls {
+		return path.Join(dir, fmt.Sprintf("%s%s", name, ext)), nil
+	}
+
+	return path.Join(dir, name, fmt.Sprintf("index%s", ext)), nil
+}
+
+func (fs *Filesystem) extension(ext string) string {
+	switch ext {
+	case ".md", ".rst": // TODO make this list configurable.  page.go has the list of markup types.
+		return ".html"
+	}
+
+	if ext != "" {
+		return ext
+	}
+
+	if fs.DefaultExtension != "" {
+		return fs.DefaultExtension
+	}
+
+	return ".html"
+}
+
+func filename(f string) string {
+	ext := path.Ext(f)
+	if ext == "" {
+		return f
+	}
+
+	return f[:len(f)-len(ext)]
+}
@@ -0,0 +1,88 @@
+package target
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// ManifestEntry is one entry in the manifest ManifestStage emits: the
+// fingerprinted path a logical path was published under, and its
+// Subresource Integrity hash.
+type ManifestEntry struct {
+	Path      string `json:"path"`
+	Integrity string `json:"integrity"`
+}
+
+// ManifestStage writes a fingerprinted copy of every published file
+// alongside the original (e.g. foo.a1b2c3d4.css next to foo.css) and, once
+// Flush is called, writes an assets.json manifest mapping each logical path
+// to its fingerprinted path and an SRI hash (e.g. for cache-busting <link>
+// tags or integrity attributes). It does not alter the content passed on to
+// later stages.
+type ManifestStage struct {
+	PublishDir string
+
+	// ManifestPath is where the manifest is written, relative to
+	// PublishDir. Defaults to "assets.json".
+	ManifestPath string
+
+	mu      sync.Mutex
+	entries map[string]ManifestEntry
+}
+
+func (s *ManifestStage) Publish(p string, r io.Reader) (io.Reader, error) {
+	content, rest, err := teeAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(content)
+	fingerprinted := fingerprintPath(p, sum[:])
+	integrity := "sha256-" + base64.StdEncoding.EncodeToString(sum[:])
+
+	dest := path.Join(s.PublishDir, fingerprinted)
+	if err := writeAtomic(filepath.FromSlash(dest), bytes.NewReader(content)); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.entries == nil {
+		s.entries = make(map[string]ManifestEntry)
+	}
+	s.entries[p] = ManifestEntry{Path: fingerprinted, Integrity: integrity}
+	s.mu.Unlock()
+
+	return rest, nil
+}
+
+// Flush writes the manifest accumulated so far to PublishDir/ManifestPath.
+// Call this once after all pages have been published.
+func (s *ManifestStage) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	manifestPath := s.ManifestPath
+	if manifestPath == "" {
+		manifestPath = "assets.json"
+	}
+
+	b, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(path.Join(s.PublishDir, manifestPath), bytes.NewReader(b))
+}
+
+func fingerprintPath(p string, sum []byte) string {
+	ext := path.Ext(p)
+	base := p[:len(p)-len(ext)]
+	return fmt.Sprintf("%s.%x%s", base, sum[:8], ext)
+}
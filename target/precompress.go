@@ -0,0 +1,89 @@
+package target
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path/filepath"
+
+	"github.com/andybalholm/brotli"
+)
+
+// GzipStage writes a foo.html.gz sibling next to every matching published
+// file, for web servers configured with e.g. nginx's gzip_static. It does
+// not alter the content passed on to later stages.
+type GzipStage struct {
+	PublishDir string
+
+	// Extensions restricts precompression to files with an extension in
+	// this set (e.g. ".html", ".css", ".js"). A nil/empty set precompresses
+	// everything.
+	Extensions map[string]bool
+}
+
+func (s GzipStage) Publish(path string, r io.Reader) (io.Reader, error) {
+	content, rest, err := teeAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if extensionAllowed(path, s.Extensions) {
+		if err := s.writeGzip(path, content); err != nil {
+			return nil, err
+		}
+	}
+
+	return rest, nil
+}
+
+func (s GzipStage) writeGzip(path string, content []byte) error {
+	var buf bytes.Buffer
+
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(content); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+
+	dest := filepath.Join(s.PublishDir, filepath.FromSlash(path)+".gz")
+	return writeAtomic(dest, &buf)
+}
+
+// BrotliStage is GzipStage's brotli equivalent, writing a foo.html.br
+// sibling for servers that prefer brotli over gzip.
+type BrotliStage struct {
+	PublishDir string
+	Extensions map[string]bool
+}
+
+func (s BrotliStage) Publish(path string, r io.Reader) (io.Reader, error) {
+	content, rest, err := teeAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if extensionAllowed(path, s.Extensions) {
+		if err := s.writeBrotli(path, content); err != nil {
+			return nil, err
+		}
+	}
+
+	return rest, nil
+}
+
+func (s BrotliStage) writeBrotli(path string, content []byte) error {
+	var buf bytes.Buffer
+
+	bw := brotli.NewWriter(&buf)
+	if _, err := bw.Write(content); err != nil {
+		return err
+	}
+	if err := bw.Close(); err != nil {
+		return err
+	}
+
+	dest := filepath.Join(s.PublishDir, filepath.FromSlash(path)+".br")
+	return writeAtomic(dest, &buf)
+}
@@ -0,0 +1,37 @@
+package target
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Stage is a single step in a Filesystem's publish pipeline. It receives the
+// already-translated destination path and the content produced by the
+// previous stage (or by the caller of Publish, for the first stage), and
+// returns the content the next stage should see.
+type Stage interface {
+	Publish(path string, r io.Reader) (io.Reader, error)
+}
+
+// teeAll reads r fully and returns its bytes alongside a fresh Reader over
+// the same bytes, so a stage can inspect or compress content without
+// consuming it for the stages that follow.
+func teeAll(r io.Reader) ([]byte, io.Reader, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, bytes.NewReader(b), nil
+}
+
+// extensionAllowed reports whether path's extension is in extensions. An
+// empty/nil set allows every extension.
+func extensionAllowed(path string, extensions map[string]bool) bool {
+	if len(extensions) == 0 {
+		return true
+	}
+	return extensions[strings.ToLower(filepath.Ext(path))]
+}
@@ -0,0 +1,141 @@
+package target
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestFilesystemPublishWritesTranslatedPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "target-publish")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := &Filesystem{PublishDir: dir}
+	if err := fs.Publish("/post/", strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadFile(filepath.Join(dir, "post", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("got %q, expected %q", got, "hello")
+	}
+}
+
+func TestGzipAndBrotliStagesWriteSiblingsAndPassThrough(t *testing.T) {
+	dir, err := ioutil.TempDir("", "target-precompress")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := &Filesystem{
+		PublishDir: dir,
+		UglyUrls:   true,
+		Stages: []Stage{
+			GzipStage{PublishDir: dir},
+			BrotliStage{PublishDir: dir},
+		},
+	}
+
+	content := "hello, precompressed world"
+	if err := fs.Publish("/post/index.html", strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadFile(filepath.Join(dir, "post", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != content {
+		t.Errorf("stage pipeline altered the content reaching disk: got %q, expected %q", got, content)
+	}
+
+	gz, err := ioutil.ReadFile(filepath.Join(dir, "post", "index.html.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gr, err := gzip.NewReader(strings.NewReader(string(gz)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decompressed, err := ioutil.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decompressed) != content {
+		t.Errorf("gzip sibling decompresses to %q, expected %q", decompressed, content)
+	}
+
+	br, err := ioutil.ReadFile(filepath.Join(dir, "post", "index.html.br"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	brDecompressed, err := ioutil.ReadAll(brotli.NewReader(strings.NewReader(string(br))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(brDecompressed) != content {
+		t.Errorf("brotli sibling decompresses to %q, expected %q", brDecompressed, content)
+	}
+}
+
+func TestManifestStageWritesFingerprintedFileAndManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "target-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := &ManifestStage{PublishDir: dir}
+	fs := &Filesystem{
+		PublishDir: dir,
+		UglyUrls:   true, // keep "style.css" as a file, not a directory with an index
+		Stages:     []Stage{manifest},
+	}
+
+	content := "body { color: red; }"
+	if err := fs.Publish("/css/style.css", strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := manifest.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "assets.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries map[string]ManifestEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, ok := entries["/css/style.css"]
+	if !ok {
+		t.Fatalf("no manifest entry for /css/style.css in %v", entries)
+	}
+
+	sum := sha256.Sum256([]byte(content))
+	wantIntegrity := "sha256-" + base64.StdEncoding.EncodeToString(sum[:])
+	if entry.Integrity != wantIntegrity {
+		t.Errorf("got integrity %q, expected %q", entry.Integrity, wantIntegrity)
+	}
+
+	fingerprinted, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(entry.Path)))
+	if err != nil {
+		t.Fatalf("the manifest's fingerprinted path %q was never written: %v", entry.Path, err)
+	}
+	if string(fingerprinted) != content {
+		t.Errorf("fingerprinted file content: got %q, expected %q", fingerprinted, content)
+	}
+}
@@ -29,7 +29,8 @@ import (
 )
 
 // Unmarshal unmarshals the data given, which can be either a string
-// or a Resource. Supported formats are JSON, TOML, YAML, and CSV.
+// or a Resource. Supported formats are JSON, TOML, YAML, CSV, XML, HCL,
+// and env/dotenv.
 // You can optional provide an Options object as the first argument.
 func (ns *Namespace) Unmarshal(args ...interface{}) (interface{}, error) {
 	if len(args) < 1 || len(args) > 2 {